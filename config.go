@@ -3,7 +3,10 @@ package kelips
 import (
 	"crypto/sha256"
 	"hash"
+	"sync"
 	"time"
+
+	"github.com/dmbreaker/go-kelips/namesys"
 )
 
 // Config is the Kelips configuration
@@ -18,6 +21,39 @@ type Config struct {
 
 	// Hash function generator default: sha256
 	HashFunc func() hash.Hash
+
+	// NodeStore persists affinity group membership and tuples across process
+	// restarts. Defaults to a non-persistent in-memory store.
+	NodeStore NodeStore
+
+	// NodeStaleAfter is the maximum age, based on LastSeen, a hydrated node
+	// may have before it's evicted on startup
+	NodeStaleAfter time.Duration
+
+	// Transport drives membership and tuple propagation. When set to a
+	// SerfTransport, it replaces the bespoke HeartbeatInterval loop with
+	// serf's gossip layer. Defaults to nil, keeping the existing heartbeat
+	// behavior.
+	Transport Transport
+
+	// NTPServers is the pool queried by the clock skew check. Defaults to
+	// pool.ntp.org.
+	NTPServers []string
+
+	// MaxAllowedClockDrift is the measured NTP offset above which a loud
+	// warning is logged, since LastSeen/expiry comparisons across nodes
+	// assume roughly synchronized clocks. Defaults to 10s. A zero or
+	// negative value disables the check.
+	MaxAllowedClockDrift time.Duration
+
+	skewMu sync.RWMutex
+	skew   *clockSkew
+
+	// Resolvers is the chain of namesys.Resolver implementations consulted,
+	// in order, to translate a human-readable name into a raw DHT key
+	// before falling into the existing Lookup path. Defaults to a DNS TXT
+	// resolver followed by a proquint resolver.
+	Resolvers []namesys.Resolver
 }
 
 // DefaultConfig returns a default sane config
@@ -28,7 +64,51 @@ func DefaultConfig() *Config {
 		HashFunc: func() hash.Hash {
 			return sha256.New()
 		},
+		NodeStore:            NewInmemNodeStore(),
+		NodeStaleAfter:       24 * time.Hour,
+		NTPServers:           defaultNTPServers,
+		MaxAllowedClockDrift: defaultMaxAllowedClockDrift,
+		Resolvers: []namesys.Resolver{
+			namesys.NewDNSResolver(),
+			namesys.NewProquintResolver(),
+		},
 	}
 
 	return conf
 }
+
+// ClockSkew returns the most recently measured offset between the local
+// clock and the configured NTP pool. It is zero until the clock skew check
+// has run at least once, which happens automatically once StartClockCheck is
+// called.
+func (c *Config) ClockSkew() time.Duration {
+	c.skewMu.RLock()
+	skew := c.skew
+	c.skewMu.RUnlock()
+
+	if skew == nil {
+		return 0
+	}
+	return skew.get()
+}
+
+// StartClockCheck starts the NTP clock skew check on the same goroutine
+// cadence as HeartbeatInterval, running until stopCh is closed, and runs it
+// once immediately regardless of HeartbeatInterval. It is a no-op if
+// MaxAllowedClockDrift is non-positive, since that disables the check
+// entirely; a non-positive HeartbeatInterval still gets the one-shot
+// startup check, since clockSkew.run itself guards against driving a ticker
+// off a non-positive interval.
+func (c *Config) StartClockCheck(stopCh <-chan struct{}) {
+	if c.MaxAllowedClockDrift <= 0 {
+		return
+	}
+
+	skew := newClockSkew(c.NTPServers, c.MaxAllowedClockDrift)
+
+	c.skewMu.Lock()
+	c.skew = skew
+	c.skewMu.Unlock()
+
+	go skew.run(c.HeartbeatInterval, stopCh)
+}
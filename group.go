@@ -16,32 +16,285 @@ var (
 	errNodeExists = errors.New("node exists")
 )
 
+// errAccessDenied is returned when a namespace's AccessPolicy rejects a read
+// or write
+var errAccessDenied = errors.New("access denied")
+
+// AccessPolicy gates reads and writes to a single namespace so an integrator
+// can restrict access (e.g. per-tenant or per-dataset) without forking the
+// routing code in localGroup.
+type AccessPolicy interface {
+	AllowRead(namespace string, key []byte) bool
+	AllowWrite(namespace string, key []byte) bool
+}
+
+// namespaceState is one isolated key space's tuples plus its optional
+// AccessPolicy
+type namespaceState struct {
+	tuples *InmemTuples
+
+	policyMu sync.RWMutex
+	policy   AccessPolicy
+}
+
+// accessPolicy returns the namespace's current AccessPolicy, or nil if none
+// is set
+func (ns *namespaceState) accessPolicy() AccessPolicy {
+	ns.policyMu.RLock()
+	defer ns.policyMu.RUnlock()
+	return ns.policy
+}
+
+// setAccessPolicy installs policy as the namespace's AccessPolicy
+func (ns *namespaceState) setAccessPolicy(policy AccessPolicy) {
+	ns.policyMu.Lock()
+	ns.policy = policy
+	ns.policyMu.Unlock()
+}
+
 type localGroup struct {
 	// Local group index
 	idx int
 
-	// Group tuples
-	tuples *InmemTuples
-
 	// all groups
 	groups affinityGroups
 
 	// hash function
 	hashFunc func() hash.Hash
+
+	// store persists this group's tuples across process restarts, the
+	// namespace counterpart to each affinityGroup's own membership store.
+	// May be nil to opt out of persistence.
+	store NodeStore
+
+	// transport broadcasts local tuple mutations to the rest of the
+	// cluster. May be nil, in which case Insert/Delete only apply locally.
+	transport Transport
+
+	// self is the local node used to advertise topics on its own behalf
+	self *hexatype.Node
+
+	// selfTopics is the set of topics this node has registered for itself,
+	// used to know which topics to gossip on each maintenance tick
+	topicMu    sync.RWMutex
+	selfTopics map[string]time.Duration
+
+	// namespaces holds an isolated *InmemTuples per namespace, all sharing
+	// this localGroup's affinity ring. Namespace and key are hashed together
+	// so different namespaces distribute independently across groups.
+	nsMu       sync.RWMutex
+	namespaces map[string]*namespaceState
 }
 
-func (lrpc *localGroup) Delete(key []byte) error {
-	return lrpc.tuples.Delete(key)
+// newLocalGroup builds a localGroup and its underlying affinityGroups from
+// conf. When conf.NodeStore is set, group membership is hydrated by
+// genAffinityGroups and this namespace's tuples are hydrated from the last
+// persisted snapshot, so a restarted process can resume without a full
+// re-bootstrap. conf.Hostname, when set, becomes the local node used to
+// advertise topics on its own behalf; conf.Transport, when set, is used to
+// broadcast local tuple mutations to the rest of the cluster.
+func newLocalGroup(conf *Config) (*localGroup, error) {
+	hashSize := int64(conf.HashFunc().Size())
+
+	groups, err := genAffinityGroups(int64(conf.NumAffinityGroups), hashSize, conf.NodeStore, conf.NodeStaleAfter)
+	if err != nil {
+		return nil, err
+	}
+
+	lrpc := &localGroup{
+		groups:     groups,
+		hashFunc:   conf.HashFunc,
+		store:      conf.NodeStore,
+		transport:  conf.Transport,
+		namespaces: make(map[string]*namespaceState),
+	}
+
+	if conf.Hostname != "" {
+		lrpc.self = &hexatype.Node{Name: conf.Hostname}
+	}
+
+	if conf.NodeStore != nil {
+		if err = lrpc.hydrateTuples(); err != nil {
+			return nil, err
+		}
+	}
+
+	return lrpc, nil
 }
 
-func (lrpc *localGroup) Insert(key []byte, tuple TupleHost) error {
-	return lrpc.tuples.Insert(key, tuple)
+// hydrateTuples loads the last persisted tuple snapshot from lrpc.store, if
+// any, repopulating each namespace's InmemTuples
+func (lrpc *localGroup) hydrateTuples() error {
+	snapshot, err := lrpc.store.LoadTuples()
+	if err != nil || snapshot == nil {
+		return err
+	}
+
+	for _, nsSnapshot := range snapshot.Namespaces {
+		ns := lrpc.namespace(nsSnapshot.Namespace)
+		for _, tuple := range nsSnapshot.Tuples {
+			for _, host := range tuple.Hosts {
+				ns.tuples.Add(tuple.Key, TupleHost(host))
+			}
+		}
+	}
+
+	return nil
 }
 
-func (lrpc *localGroup) LookupGroupNodes(key []byte) ([]*hexatype.Node, error) {
+// persistInsert writes the current host list for key back to lrpc.store so
+// only the mutated key is re-serialized, rather than re-snapshotting every
+// namespace's tuples on every write. It is a no-op when no store is
+// configured.
+func (lrpc *localGroup) persistInsert(namespace string, key []byte, ns *namespaceState) {
+	if lrpc.store == nil {
+		return
+	}
+
+	hosts, err := ns.tuples.Get(key)
+	if err != nil {
+		log.Printf("[ERROR] Failed to read tuple for persistence namespace=%s key=%x: %v", namespace, key, err)
+		return
+	}
+
+	if err = lrpc.store.PutTuple(namespace, key, hosts); err != nil {
+		log.Printf("[ERROR] Failed to persist tuple insert namespace=%s key=%x: %v", namespace, key, err)
+	}
+}
+
+// persistDelete removes key's persisted entry from lrpc.store. It is a no-op
+// when no store is configured.
+func (lrpc *localGroup) persistDelete(namespace string, key []byte) {
+	if lrpc.store == nil {
+		return
+	}
+
+	if err := lrpc.store.DeleteTuple(namespace, key); err != nil {
+		log.Printf("[ERROR] Failed to persist tuple delete namespace=%s key=%x: %v", namespace, key, err)
+	}
+}
+
+// namespace returns the namespaceState for name, creating it on first use
+func (lrpc *localGroup) namespace(name string) *namespaceState {
+	lrpc.nsMu.RLock()
+	ns, ok := lrpc.namespaces[name]
+	lrpc.nsMu.RUnlock()
+	if ok {
+		return ns
+	}
+
+	lrpc.nsMu.Lock()
+	defer lrpc.nsMu.Unlock()
+	if ns, ok = lrpc.namespaces[name]; ok {
+		return ns
+	}
+
+	ns = &namespaceState{tuples: NewInmemTuples()}
+	lrpc.namespaces[name] = ns
+	return ns
+}
+
+// ListNamespaces returns the set of namespaces currently hosted by this
+// localGroup
+func (lrpc *localGroup) ListNamespaces() []string {
+	lrpc.nsMu.RLock()
+	defer lrpc.nsMu.RUnlock()
+
+	names := make([]string, 0, len(lrpc.namespaces))
+	for name := range lrpc.namespaces {
+		names = append(names, name)
+	}
+	return names
+}
+
+// SetAccessPolicy installs policy as the AccessPolicy gating reads and
+// writes to namespace
+func (lrpc *localGroup) SetAccessPolicy(namespace string, policy AccessPolicy) {
+	ns := lrpc.namespace(namespace)
+	ns.setAccessPolicy(policy)
+}
+
+// hashNamespaceKey hashes namespace and key together so placement is scoped
+// to the namespace: the same key in two namespaces can land in different
+// affinity groups.
+func (lrpc *localGroup) hashNamespaceKey(namespace string, key []byte) []byte {
 	h := lrpc.hashFunc()
+	h.Write([]byte(namespace))
 	h.Write(key)
-	sh := h.Sum(nil)
+	return h.Sum(nil)
+}
+
+// applyDelete removes key from namespace and persists the change locally,
+// without broadcasting it. It is shared by Delete, which broadcasts the
+// mutation to the rest of the cluster, and HandleDelete, which applies a
+// mutation a peer already broadcast and so must not re-broadcast it.
+func (lrpc *localGroup) applyDelete(namespace string, key []byte) error {
+	ns := lrpc.namespace(namespace)
+	if policy := ns.accessPolicy(); policy != nil && !policy.AllowWrite(namespace, key) {
+		return errAccessDenied
+	}
+
+	if err := ns.tuples.Delete(key); err != nil {
+		return err
+	}
+
+	lrpc.persistDelete(namespace, key)
+	return nil
+}
+
+// applyInsert adds tuple under key in namespace and persists the change
+// locally, without broadcasting it. See applyDelete for why this is split
+// out from Insert/HandleInsert.
+func (lrpc *localGroup) applyInsert(namespace string, key []byte, tuple TupleHost) error {
+	ns := lrpc.namespace(namespace)
+	if policy := ns.accessPolicy(); policy != nil && !policy.AllowWrite(namespace, key) {
+		return errAccessDenied
+	}
+
+	if err := ns.tuples.Insert(key, tuple); err != nil {
+		return err
+	}
+
+	lrpc.persistInsert(namespace, key, ns)
+	return nil
+}
+
+// Delete removes key from namespace and broadcasts the deletion over
+// lrpc.transport, if one is configured, so peers converge without a separate
+// RPC round trip.
+func (lrpc *localGroup) Delete(namespace string, key []byte) error {
+	if err := lrpc.applyDelete(namespace, key); err != nil {
+		return err
+	}
+
+	if lrpc.transport != nil {
+		if err := lrpc.transport.BroadcastDelete(namespace, key); err != nil {
+			log.Printf("[ERROR] Failed to broadcast tuple delete namespace=%s key=%x: %v", namespace, key, err)
+		}
+	}
+
+	return nil
+}
+
+// Insert adds tuple under key in namespace and broadcasts the insertion over
+// lrpc.transport, if one is configured, so peers converge without a separate
+// RPC round trip.
+func (lrpc *localGroup) Insert(namespace string, key []byte, tuple TupleHost) error {
+	if err := lrpc.applyInsert(namespace, key, tuple); err != nil {
+		return err
+	}
+
+	if lrpc.transport != nil {
+		if err := lrpc.transport.BroadcastInsert(namespace, key, tuple); err != nil {
+			log.Printf("[ERROR] Failed to broadcast tuple insert namespace=%s key=%x: %v", namespace, key, err)
+		}
+	}
+
+	return nil
+}
+
+func (lrpc *localGroup) LookupGroupNodes(namespace string, key []byte) ([]*hexatype.Node, error) {
+	sh := lrpc.hashNamespaceKey(namespace, key)
 
 	group := lrpc.groups.get(sh)
 	n := group.Nodes()
@@ -52,8 +305,13 @@ func (lrpc *localGroup) LookupGroupNodes(key []byte) ([]*hexatype.Node, error) {
 	return nodes, nil
 }
 
-func (lrpc *localGroup) Lookup(key []byte) ([]*hexatype.Node, error) {
-	tuples, err := lrpc.tuples.Get(key)
+func (lrpc *localGroup) Lookup(namespace string, key []byte) ([]*hexatype.Node, error) {
+	ns := lrpc.namespace(namespace)
+	if policy := ns.accessPolicy(); policy != nil && !policy.AllowRead(namespace, key) {
+		return nil, errAccessDenied
+	}
+
+	tuples, err := ns.tuples.Get(key)
 	if err != nil {
 		return nil, err
 	}
@@ -73,21 +331,42 @@ func (lrpc *localGroup) Lookup(key []byte) ([]*hexatype.Node, error) {
 	return nodes, nil
 }
 
-func (lrpc *localGroup) Snapshot() *Snapshot {
-	snapshot := &Snapshot{
-		Tuples: make([]*Tuple, 0, lrpc.tuples.Count()),
-		Nodes:  make([]*hexatype.Node, 0, lrpc.groups.nodeCount()),
+// NamespaceSnapshot is the tuple contents of a single namespace
+type NamespaceSnapshot struct {
+	Namespace string
+	Tuples    []*Tuple
+}
+
+// MultiSnapshot is a point-in-time snapshot of every namespace hosted by a
+// localGroup along with the nodes known across its affinity groups
+type MultiSnapshot struct {
+	Namespaces []*NamespaceSnapshot
+	Nodes      []*hexatype.Node
+}
+
+func (lrpc *localGroup) Snapshot() *MultiSnapshot {
+	names := lrpc.ListNamespaces()
+
+	snapshot := &MultiSnapshot{
+		Namespaces: make([]*NamespaceSnapshot, 0, len(names)),
+		Nodes:      make([]*hexatype.Node, 0, lrpc.groups.nodeCount()),
 	}
 
-	// handle all tuples
-	lrpc.tuples.Iter(func(key []byte, hosts []TupleHost) bool {
-		tuple := &Tuple{Key: key, Hosts: make([][]byte, 0, len(hosts))}
-		for _, h := range hosts {
-			tuple.Hosts = append(tuple.Hosts, h)
-		}
-		snapshot.Tuples = append(snapshot.Tuples, tuple)
-		return true
-	})
+	for _, name := range names {
+		ns := lrpc.namespace(name)
+		nsSnapshot := &NamespaceSnapshot{Namespace: name, Tuples: make([]*Tuple, 0, ns.tuples.Count())}
+
+		ns.tuples.Iter(func(key []byte, hosts []TupleHost) bool {
+			tuple := &Tuple{Key: key, Hosts: make([][]byte, 0, len(hosts))}
+			for _, h := range hosts {
+				tuple.Hosts = append(tuple.Hosts, h)
+			}
+			nsSnapshot.Tuples = append(nsSnapshot.Tuples, tuple)
+			return true
+		})
+
+		snapshot.Namespaces = append(snapshot.Namespaces, nsSnapshot)
+	}
 
 	lrpc.groups.iterNodes(func(node hexatype.Node) bool {
 		snapshot.Nodes = append(snapshot.Nodes, &node)
@@ -105,19 +384,94 @@ type affinityGroup struct {
 	// k value of this group
 	index int
 
+	// store persists this group's membership so it can be rehydrated across
+	// process restarts. May be nil for groups that opt out of persistence.
+	store NodeStore
+
 	// Nodes part of the affinity group
 	mu sync.RWMutex
 	m  map[string]*hexatype.Node
+
+	// topics holds the bounded advertiser FIFO for each topic whose hash
+	// routes to this group, keyed by topic name
+	topicMu sync.RWMutex
+	topics  map[string]*topicQueue
 }
 
 func newAffinityGroup(id []byte, index int) *affinityGroup {
 	return &affinityGroup{
-		id:    id,
-		index: index,
-		m:     make(map[string]*hexatype.Node),
+		id:     id,
+		index:  index,
+		m:      make(map[string]*hexatype.Node),
+		topics: make(map[string]*topicQueue),
+	}
+}
+
+// topicQueue returns the topicQueue for topic, creating it on first use
+func (group *affinityGroup) topicQueue(topic string) *topicQueue {
+	group.topicMu.RLock()
+	tq, ok := group.topics[topic]
+	group.topicMu.RUnlock()
+	if ok {
+		return tq
+	}
+
+	group.topicMu.Lock()
+	defer group.topicMu.Unlock()
+	if tq, ok = group.topics[topic]; ok {
+		return tq
+	}
+
+	tq = newTopicQueue()
+	group.topics[topic] = tq
+	return tq
+}
+
+// peekTopicQueue returns the topicQueue for topic without creating one
+func (group *affinityGroup) peekTopicQueue(topic string) (*topicQueue, bool) {
+	group.topicMu.RLock()
+	defer group.topicMu.RUnlock()
+	tq, ok := group.topics[topic]
+	return tq, ok
+}
+
+// expireTopics drops stale advertisements from every topic routed to this
+// group
+func (group *affinityGroup) expireTopics() {
+	group.topicMu.RLock()
+	defer group.topicMu.RUnlock()
+
+	for _, tq := range group.topics {
+		tq.expire()
 	}
 }
 
+// newPersistentAffinityGroup creates an affinityGroup hydrated from store,
+// evicting any persisted node whose LastSeen exceeds staleAfter so a
+// restarted process doesn't seed its groups with long-dead peers.
+func newPersistentAffinityGroup(id []byte, index int, store NodeStore, staleAfter time.Duration) (*affinityGroup, error) {
+	group := newAffinityGroup(id, index)
+	group.store = store
+
+	records, err := store.ListNodes()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rec := range records {
+		if rec.GroupIndex != index {
+			continue
+		}
+		if rec.stale(staleAfter) {
+			store.DeleteNode(rec.Node.Host())
+			continue
+		}
+		group.m[rec.Node.Host()] = rec.Node
+	}
+
+	return group, nil
+}
+
 func (group *affinityGroup) count() int {
 	group.mu.RLock()
 	defer group.mu.RUnlock()
@@ -165,6 +519,10 @@ func (group *affinityGroup) pingNode(hostname string, coord *vivaldi.Coordinate,
 
 	group.mu.Unlock()
 
+	if group.store != nil {
+		group.store.PutNode(group.index, node)
+	}
+
 	//log.Println("[DEBUG] Pinged", hostname, rtt)
 
 	return nil
@@ -183,6 +541,10 @@ func (group *affinityGroup) removeNode(hostname string) error {
 	delete(group.m, hostname)
 	group.mu.Unlock()
 
+	if group.store != nil {
+		group.store.DeleteNode(hostname)
+	}
+
 	log.Printf("[INFO] Node removed group=%d count=%d node=%s", group.index,
 		len(group.m), hostname)
 
@@ -203,6 +565,10 @@ func (group *affinityGroup) addNode(node *hexatype.Node, force bool) error {
 	group.m[node.Host()] = node
 	group.mu.Unlock()
 
+	if group.store != nil {
+		group.store.PutNode(group.index, node)
+	}
+
 	log.Printf("[INFO] Node added group=%d count=%d host=%s", group.index, len(group.m), node.Host())
 
 	return nil
@@ -3,6 +3,7 @@ package kelips
 import (
 	"bytes"
 	"math/big"
+	"time"
 
 	"github.com/hexablock/hexatype"
 )
@@ -89,7 +90,10 @@ RETRY:
 	return group
 }
 
-func genAffinityGroups(numGroups int64, hashSize int64) affinityGroups {
+// genAffinityGroups divides the hash keyspace into numGroups affinity
+// groups. When store is non-nil each group is hydrated from it, evicting any
+// persisted node whose LastSeen exceeds staleAfter.
+func genAffinityGroups(numGroups int64, hashSize int64, store NodeStore, staleAfter time.Duration) (affinityGroups, error) {
 	// Calculate the size of the keyspace
 	var keyspace big.Int
 	keyspace.Exp(big.NewInt(2), big.NewInt(hashSize*8), nil)
@@ -98,14 +102,28 @@ func genAffinityGroups(numGroups int64, hashSize int64) affinityGroups {
 	// Size of each group given the keyspace
 	groupSize := new(big.Int).Div(&keyspace, k)
 
-	ags := make([]*affinityGroup, numGroups)
+	ids := make([][]byte, numGroups)
 	// First group i.e. 0 group
-	ags[0] = newAffinityGroup(make([]byte, hashSize), 0)
+	ids[0] = make([]byte, hashSize)
 	// Generate the remainder groups
 	for i := int64(1); i < numGroups; i++ {
 		gi := new(big.Int).Mul(big.NewInt(i), groupSize)
-		ags[i] = newAffinityGroup(gi.Bytes(), int(i))
+		ids[i] = gi.Bytes()
+	}
+
+	ags := make([]*affinityGroup, numGroups)
+	for i, id := range ids {
+		if store == nil {
+			ags[i] = newAffinityGroup(id, i)
+			continue
+		}
+
+		group, err := newPersistentAffinityGroup(id, i, store, staleAfter)
+		if err != nil {
+			return nil, err
+		}
+		ags[i] = group
 	}
 
-	return affinityGroups(ags)
+	return affinityGroups(ags), nil
 }
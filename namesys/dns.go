@@ -0,0 +1,51 @@
+package namesys
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net"
+
+	"github.com/mr-tron/base58"
+)
+
+// dnsRecordPrefix namespaces the TXT record queried for a name, following
+// the `_kelips.<name>` convention used by IPFS's namesys for `_dnslink`
+const dnsRecordPrefix = "_kelips."
+
+// DNSResolver resolves a name to a DHT key by looking up a hex or
+// base58-encoded `_kelips.<name>` TXT record
+type DNSResolver struct {
+	// Resolver is used to issue the TXT lookup; defaults to net.DefaultResolver
+	Resolver *net.Resolver
+}
+
+// NewDNSResolver returns a DNSResolver using net.DefaultResolver
+func NewDNSResolver() *DNSResolver {
+	return &DNSResolver{Resolver: net.DefaultResolver}
+}
+
+// Resolve looks up the `_kelips.<name>` TXT record and decodes its value as
+// a hex or base58-encoded key
+func (d *DNSResolver) Resolve(ctx context.Context, name string) ([]byte, error) {
+	resolver := d.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+
+	records, err := resolver.LookupTXT(ctx, dnsRecordPrefix+name)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, record := range records {
+		if key, err := hex.DecodeString(record); err == nil {
+			return key, nil
+		}
+		if key, err := base58.Decode(record); err == nil {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("namesys: no decodable TXT record for %s", name)
+}
@@ -0,0 +1,91 @@
+package namesys
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+const (
+	proquintConsonants = "bdfghjklmnprstvz"
+	proquintVowels     = "aiou"
+)
+
+// ProquintResolver decodes pronounceable identifiers like
+// "lusab-babad-gutih" back into their underlying 16-bit-per-quint byte
+// sequence for direct DHT lookup.
+type ProquintResolver struct{}
+
+// NewProquintResolver returns a ProquintResolver
+func NewProquintResolver() *ProquintResolver {
+	return &ProquintResolver{}
+}
+
+// Resolve decodes name as a dash-separated sequence of 5-character proquint
+// words into its raw byte sequence. It returns ErrNotResolvable if name
+// doesn't look like a proquint.
+func (p *ProquintResolver) Resolve(_ context.Context, name string) ([]byte, error) {
+	words := strings.Split(name, "-")
+
+	out := make([]byte, 0, len(words)*2)
+	for _, word := range words {
+		word16, err := decodeProquintWord(word)
+		if err != nil {
+			return nil, ErrNotResolvable
+		}
+
+		buf := make([]byte, 2)
+		binary.BigEndian.PutUint16(buf, word16)
+		out = append(out, buf...)
+	}
+
+	return out, nil
+}
+
+// decodeProquintWord decodes a single consonant-vowel-consonant-vowel-
+// consonant word into its 16-bit value
+func decodeProquintWord(word string) (uint16, error) {
+	if len(word) != 5 {
+		return 0, fmt.Errorf("namesys: invalid proquint word length: %s", word)
+	}
+
+	c0, err := proquintConsonant(word[0])
+	if err != nil {
+		return 0, err
+	}
+	v0, err := proquintVowel(word[1])
+	if err != nil {
+		return 0, err
+	}
+	c1, err := proquintConsonant(word[2])
+	if err != nil {
+		return 0, err
+	}
+	v1, err := proquintVowel(word[3])
+	if err != nil {
+		return 0, err
+	}
+	c2, err := proquintConsonant(word[4])
+	if err != nil {
+		return 0, err
+	}
+
+	return c0<<12 | v0<<10 | c1<<6 | v1<<4 | c2, nil
+}
+
+func proquintConsonant(c byte) (uint16, error) {
+	i := strings.IndexByte(proquintConsonants, c)
+	if i < 0 {
+		return 0, fmt.Errorf("namesys: invalid proquint consonant: %c", c)
+	}
+	return uint16(i), nil
+}
+
+func proquintVowel(c byte) (uint16, error) {
+	i := strings.IndexByte(proquintVowels, c)
+	if i < 0 {
+		return 0, fmt.Errorf("namesys: invalid proquint vowel: %c", c)
+	}
+	return uint16(i), nil
+}
@@ -0,0 +1,43 @@
+// Package namesys translates human-readable names into the raw []byte keys
+// Kelips already knows how to route, the way IPFS's namesys package sits
+// above its DHT. It has no knowledge of Kelips' affinity groups or nodes;
+// callers take the resolved key and hand it to the existing Lookup path.
+package namesys
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNotResolvable is returned by a Resolver that doesn't recognize name's
+// format, letting a Chain fall through to the next resolver
+var ErrNotResolvable = errors.New("namesys: name not resolvable")
+
+// Resolver recovers the raw DHT key a human-readable name refers to
+type Resolver interface {
+	Resolve(ctx context.Context, name string) ([]byte, error)
+}
+
+// Chain tries each Resolver in order, returning the first successful
+// resolution. Integrators can append their own Resolver (e.g. an on-chain
+// one) without touching the core resolvers.
+type Chain []Resolver
+
+// Resolve tries each resolver in the chain in order, returning the first key
+// that resolves successfully
+func (c Chain) Resolve(ctx context.Context, name string) ([]byte, error) {
+	var err error
+
+	for _, resolver := range c {
+		var key []byte
+		key, err = resolver.Resolve(ctx, name)
+		if err == nil {
+			return key, nil
+		}
+	}
+
+	if err == nil {
+		err = ErrNotResolvable
+	}
+	return nil, err
+}
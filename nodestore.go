@@ -0,0 +1,135 @@
+package kelips
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hexablock/hexatype"
+)
+
+// NodeStoreRecord pins a persisted node to the affinity group it belonged to
+// so a restarted process can rehydrate its groups without a full
+// re-bootstrap.
+type NodeStoreRecord struct {
+	GroupIndex int
+	Node       *hexatype.Node
+}
+
+// stale reports whether the record's LastSeen is older than staleAfter
+func (r *NodeStoreRecord) stale(staleAfter time.Duration) bool {
+	age := time.Duration(time.Now().UnixNano()-r.Node.LastSeen) * time.Nanosecond
+	return age > staleAfter
+}
+
+// NodeStore persists affinityGroup membership, vivaldi coordinates,
+// heartbeats and LastSeen timestamps, along with the InmemTuples contents,
+// across process restarts.
+type NodeStore interface {
+	// PutNode upserts a node record under the given affinity group index
+	PutNode(groupIndex int, node *hexatype.Node) error
+	// DeleteNode removes a persisted node record by hostname
+	DeleteNode(hostname string) error
+	// ListNodes returns every persisted record, used to hydrate
+	// affinityGroups on startup
+	ListNodes() ([]*NodeStoreRecord, error)
+	// PutTuple upserts the host list for a single namespace+key, the tuple
+	// counterpart to PutNode. Called on every Insert so a write only
+	// re-serializes the mutated key, not the whole tuple set.
+	PutTuple(namespace string, key []byte, hosts []TupleHost) error
+	// DeleteTuple removes a single namespace+key's persisted entry
+	DeleteTuple(namespace string, key []byte) error
+	// LoadTuples returns every persisted namespace's tuples, used to
+	// hydrate a localGroup's InmemTuples on startup
+	LoadTuples() (*MultiSnapshot, error)
+	// Close releases any resources held by the store
+	Close() error
+}
+
+// inmemNodeStore is the default NodeStore, kept for backwards compatibility
+// with configs that don't set one. It does not actually persist anything
+// across restarts.
+type inmemNodeStore struct {
+	mu      sync.RWMutex
+	records map[string]*NodeStoreRecord
+
+	// tuples is namespace -> key -> host list, mirroring the per-key
+	// storage the on-disk backends use so behavior matches across stores
+	tuples map[string]map[string][]TupleHost
+}
+
+// NewInmemNodeStore returns the default, non-persistent NodeStore
+func NewInmemNodeStore() NodeStore {
+	return &inmemNodeStore{
+		records: make(map[string]*NodeStoreRecord),
+		tuples:  make(map[string]map[string][]TupleHost),
+	}
+}
+
+func (s *inmemNodeStore) PutNode(groupIndex int, node *hexatype.Node) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[node.Host()] = &NodeStoreRecord{GroupIndex: groupIndex, Node: node}
+	return nil
+}
+
+func (s *inmemNodeStore) DeleteNode(hostname string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, hostname)
+	return nil
+}
+
+func (s *inmemNodeStore) ListNodes() ([]*NodeStoreRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]*NodeStoreRecord, 0, len(s.records))
+	for _, rec := range s.records {
+		out = append(out, rec)
+	}
+	return out, nil
+}
+
+func (s *inmemNodeStore) PutTuple(namespace string, key []byte, hosts []TupleHost) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys, ok := s.tuples[namespace]
+	if !ok {
+		keys = make(map[string][]TupleHost)
+		s.tuples[namespace] = keys
+	}
+	keys[string(key)] = hosts
+	return nil
+}
+
+func (s *inmemNodeStore) DeleteTuple(namespace string, key []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if keys, ok := s.tuples[namespace]; ok {
+		delete(keys, string(key))
+	}
+	return nil
+}
+
+func (s *inmemNodeStore) LoadTuples() (*MultiSnapshot, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snapshot := &MultiSnapshot{Namespaces: make([]*NamespaceSnapshot, 0, len(s.tuples))}
+	for namespace, keys := range s.tuples {
+		nsSnapshot := &NamespaceSnapshot{Namespace: namespace, Tuples: make([]*Tuple, 0, len(keys))}
+		for key, hosts := range keys {
+			tuple := &Tuple{Key: []byte(key), Hosts: make([][]byte, 0, len(hosts))}
+			for _, h := range hosts {
+				tuple.Hosts = append(tuple.Hosts, h)
+			}
+			nsSnapshot.Tuples = append(nsSnapshot.Tuples, tuple)
+		}
+		snapshot.Namespaces = append(snapshot.Namespaces, nsSnapshot)
+	}
+	return snapshot, nil
+}
+
+func (s *inmemNodeStore) Close() error { return nil }
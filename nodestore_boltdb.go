@@ -0,0 +1,154 @@
+package kelips
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/boltdb/bolt"
+	"github.com/hexablock/hexatype"
+)
+
+var (
+	boltNodesBucket = []byte("nodes")
+
+	// boltTuplesBucket holds one key per namespace+key tuple entry, keyed by
+	// tupleBucketKey, so a single Insert/Delete only touches its own key
+	// instead of rewriting a whole-DB snapshot
+	boltTuplesBucket = []byte("tuples")
+)
+
+func tupleBucketKey(namespace string, key []byte) []byte {
+	k := make([]byte, 0, len(namespace)+1+len(key))
+	k = append(k, namespace...)
+	k = append(k, 0)
+	k = append(k, key...)
+	return k
+}
+
+// splitTupleBucketKey recovers the namespace and tuple key encoded by
+// tupleBucketKey
+func splitTupleBucketKey(raw []byte) (namespace string, key []byte) {
+	i := bytes.IndexByte(raw, 0)
+	if i < 0 {
+		return "", raw
+	}
+	return string(raw[:i]), raw[i+1:]
+}
+
+// BoltDBNodeStore is a NodeStore backed by a BoltDB database
+type BoltDBNodeStore struct {
+	db *bolt.DB
+}
+
+// NewBoltDBNodeStore opens (or creates) a BoltDB database at path to use as a
+// NodeStore
+func NewBoltDBNodeStore(path string) (*BoltDBNodeStore, error) {
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(boltNodesBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(boltTuplesBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &BoltDBNodeStore{db: db}, nil
+}
+
+func (s *BoltDBNodeStore) PutNode(groupIndex int, node *hexatype.Node) error {
+	rec := &NodeStoreRecord{GroupIndex: groupIndex, Node: node}
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltNodesBucket).Put([]byte(node.Host()), b)
+	})
+}
+
+func (s *BoltDBNodeStore) DeleteNode(hostname string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltNodesBucket).Delete([]byte(hostname))
+	})
+}
+
+func (s *BoltDBNodeStore) ListNodes() ([]*NodeStoreRecord, error) {
+	var out []*NodeStoreRecord
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltNodesBucket).ForEach(func(_, v []byte) error {
+			rec := &NodeStoreRecord{}
+			if err := json.Unmarshal(v, rec); err != nil {
+				return err
+			}
+			out = append(out, rec)
+			return nil
+		})
+	})
+
+	return out, err
+}
+
+func (s *BoltDBNodeStore) PutTuple(namespace string, key []byte, hosts []TupleHost) error {
+	b, err := json.Marshal(hosts)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltTuplesBucket).Put(tupleBucketKey(namespace, key), b)
+	})
+}
+
+func (s *BoltDBNodeStore) DeleteTuple(namespace string, key []byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltTuplesBucket).Delete(tupleBucketKey(namespace, key))
+	})
+}
+
+func (s *BoltDBNodeStore) LoadTuples() (*MultiSnapshot, error) {
+	byNamespace := make(map[string][]*Tuple)
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltTuplesBucket).ForEach(func(k, v []byte) error {
+			namespace, key := splitTupleBucketKey(k)
+
+			var hosts []TupleHost
+			if err := json.Unmarshal(v, &hosts); err != nil {
+				return err
+			}
+
+			keyCopy := make([]byte, len(key))
+			copy(keyCopy, key)
+
+			tuple := &Tuple{Key: keyCopy, Hosts: make([][]byte, 0, len(hosts))}
+			for _, h := range hosts {
+				tuple.Hosts = append(tuple.Hosts, h)
+			}
+			byNamespace[namespace] = append(byNamespace[namespace], tuple)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &MultiSnapshot{Namespaces: make([]*NamespaceSnapshot, 0, len(byNamespace))}
+	for namespace, tuples := range byNamespace {
+		snapshot.Namespaces = append(snapshot.Namespaces, &NamespaceSnapshot{Namespace: namespace, Tuples: tuples})
+	}
+	return snapshot, nil
+}
+
+func (s *BoltDBNodeStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,132 @@
+package kelips
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/hexablock/hexatype"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// nodePrefix namespaces node records away from tuple records in the shared
+// keyspace
+var nodePrefix = []byte("n/")
+
+// tuplePrefix namespaces tuple records away from node records in the shared
+// keyspace. Each key is tuplePrefix + namespace + a NUL separator + the
+// tuple key, so a single namespace+key can be upserted/deleted without
+// touching any other entry.
+var tuplePrefix = []byte("t/")
+
+func tupleStoreKey(namespace string, key []byte) []byte {
+	k := make([]byte, 0, len(tuplePrefix)+len(namespace)+1+len(key))
+	k = append(k, tuplePrefix...)
+	k = append(k, namespace...)
+	k = append(k, 0)
+	k = append(k, key...)
+	return k
+}
+
+// splitTupleStoreKey recovers the namespace and tuple key encoded by
+// tupleStoreKey
+func splitTupleStoreKey(raw []byte) (namespace string, key []byte) {
+	raw = raw[len(tuplePrefix):]
+	i := bytes.IndexByte(raw, 0)
+	if i < 0 {
+		return "", raw
+	}
+	return string(raw[:i]), raw[i+1:]
+}
+
+// LevelDBNodeStore is a NodeStore backed by a LevelDB database
+type LevelDBNodeStore struct {
+	db *leveldb.DB
+}
+
+// NewLevelDBNodeStore opens (or creates) a LevelDB database at path to use as
+// a NodeStore
+func NewLevelDBNodeStore(path string) (*LevelDBNodeStore, error) {
+	db, err := leveldb.OpenFile(path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &LevelDBNodeStore{db: db}, nil
+}
+
+func (s *LevelDBNodeStore) PutNode(groupIndex int, node *hexatype.Node) error {
+	rec := &NodeStoreRecord{GroupIndex: groupIndex, Node: node}
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return s.db.Put(append(nodePrefix, []byte(node.Host())...), b, nil)
+}
+
+func (s *LevelDBNodeStore) DeleteNode(hostname string) error {
+	return s.db.Delete(append(nodePrefix, []byte(hostname)...), nil)
+}
+
+func (s *LevelDBNodeStore) ListNodes() ([]*NodeStoreRecord, error) {
+	iter := s.db.NewIterator(util.BytesPrefix(nodePrefix), nil)
+	defer iter.Release()
+
+	var out []*NodeStoreRecord
+	for iter.Next() {
+		rec := &NodeStoreRecord{}
+		if err := json.Unmarshal(iter.Value(), rec); err != nil {
+			return nil, err
+		}
+		out = append(out, rec)
+	}
+	return out, iter.Error()
+}
+
+func (s *LevelDBNodeStore) PutTuple(namespace string, key []byte, hosts []TupleHost) error {
+	b, err := json.Marshal(hosts)
+	if err != nil {
+		return err
+	}
+	return s.db.Put(tupleStoreKey(namespace, key), b, nil)
+}
+
+func (s *LevelDBNodeStore) DeleteTuple(namespace string, key []byte) error {
+	return s.db.Delete(tupleStoreKey(namespace, key), nil)
+}
+
+func (s *LevelDBNodeStore) LoadTuples() (*MultiSnapshot, error) {
+	iter := s.db.NewIterator(util.BytesPrefix(tuplePrefix), nil)
+	defer iter.Release()
+
+	byNamespace := make(map[string][]*Tuple)
+	for iter.Next() {
+		namespace, key := splitTupleStoreKey(iter.Key())
+
+		var hosts []TupleHost
+		if err := json.Unmarshal(iter.Value(), &hosts); err != nil {
+			return nil, err
+		}
+
+		keyCopy := make([]byte, len(key))
+		copy(keyCopy, key)
+
+		tuple := &Tuple{Key: keyCopy, Hosts: make([][]byte, 0, len(hosts))}
+		for _, h := range hosts {
+			tuple.Hosts = append(tuple.Hosts, h)
+		}
+		byNamespace[namespace] = append(byNamespace[namespace], tuple)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, err
+	}
+
+	snapshot := &MultiSnapshot{Namespaces: make([]*NamespaceSnapshot, 0, len(byNamespace))}
+	for namespace, tuples := range byNamespace {
+		snapshot.Namespaces = append(snapshot.Namespaces, &NamespaceSnapshot{Namespace: namespace, Tuples: tuples})
+	}
+	return snapshot, nil
+}
+
+func (s *LevelDBNodeStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,117 @@
+package kelips
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/beevik/ntp"
+	"github.com/hexablock/log"
+)
+
+// defaultNTPServers is queried when Config.NTPServers is unset
+var defaultNTPServers = []string{"pool.ntp.org"}
+
+// defaultMaxAllowedClockDrift is the MaxAllowedClockDrift set by
+// DefaultConfig, modeled on discv5's clock-drift threshold. A Config whose
+// MaxAllowedClockDrift is left at zero has the check disabled instead.
+const defaultMaxAllowedClockDrift = 10 * time.Second
+
+// clockSkew periodically samples a pool of NTP servers and tracks the local
+// node's measured offset from true time. affinityGroup.pingNode/addNode stamp
+// LastSeen from the local clock, so a badly drifted clock can make otherwise
+// live nodes look stale (or stale nodes look live) to ExpireHost and the
+// NodeStore staleness eviction.
+type clockSkew struct {
+	mu       sync.RWMutex
+	offset   time.Duration
+	servers  []string
+	maxDrift time.Duration
+}
+
+func newClockSkew(servers []string, maxDrift time.Duration) *clockSkew {
+	if len(servers) == 0 {
+		servers = defaultNTPServers
+	}
+	if maxDrift <= 0 {
+		maxDrift = defaultMaxAllowedClockDrift
+	}
+
+	return &clockSkew{servers: servers, maxDrift: maxDrift}
+}
+
+// check queries every configured NTP server, records the median clock offset
+// and emits a warning if it exceeds maxDrift
+func (c *clockSkew) check() {
+	samples := make([]time.Duration, 0, len(c.servers))
+	for _, server := range c.servers {
+		resp, err := ntp.Query(server)
+		if err != nil {
+			log.Printf("[WARN] NTP query failed server=%s error=%v", server, err)
+			continue
+		}
+		samples = append(samples, resp.ClockOffset)
+	}
+
+	if len(samples) == 0 {
+		log.Printf("[WARN] NTP clock skew check failed: no servers responded")
+		return
+	}
+
+	offset := medianDuration(samples)
+
+	c.mu.Lock()
+	c.offset = offset
+	c.mu.Unlock()
+
+	drift := offset
+	if drift < 0 {
+		drift = -drift
+	}
+	if drift > c.maxDrift {
+		log.Printf("[WARN] Local clock drift %s exceeds max allowed drift %s; LastSeen comparisons across nodes may be unreliable", offset, c.maxDrift)
+	}
+}
+
+// get returns the last measured clock offset
+func (c *clockSkew) get() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.offset
+}
+
+// run queries the NTP pool immediately and then on every tick of interval,
+// the same cadence used to drive the heartbeat loop, until stopCh closes. A
+// non-positive interval can't drive a ticker, so run checks once and returns
+// instead of panicking.
+func (c *clockSkew) run(interval time.Duration, stopCh <-chan struct{}) {
+	c.check()
+
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.check()
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+func medianDuration(samples []time.Duration) time.Duration {
+	sorted := make([]time.Duration, len(samples))
+	copy(sorted, samples)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
@@ -0,0 +1,49 @@
+package kelips
+
+import (
+	"context"
+	"errors"
+
+	"github.com/dmbreaker/go-kelips/namesys"
+	"github.com/hexablock/hexatype"
+)
+
+// errNameNotResolved is returned when no resolver in the chain could
+// translate a name into a raw DHT key and the last one tried reported
+// namesys.ErrNotResolvable; any other error from it is returned unchanged
+var errNameNotResolved = errors.New("name could not be resolved")
+
+// NameResolver sits above a localGroup's Lookup, translating human-readable
+// names into the raw keys the DHT already routes using a namesys.Chain of
+// Resolver implementations (e.g. DNS TXT records, proquints).
+type NameResolver struct {
+	namespace string
+	lrpc      *localGroup
+	chain     namesys.Chain
+}
+
+// newNameResolver returns a NameResolver that resolves names into tuple
+// hosts within namespace
+func newNameResolver(lrpc *localGroup, namespace string, resolvers []namesys.Resolver) *NameResolver {
+	return &NameResolver{namespace: namespace, lrpc: lrpc, chain: namesys.Chain(resolvers)}
+}
+
+// NameResolver returns a NameResolver for namespace using conf's configured
+// resolver chain
+func (lrpc *localGroup) NameResolver(namespace string, conf *Config) *NameResolver {
+	return newNameResolver(lrpc, namespace, conf.Resolvers)
+}
+
+// Resolve recovers the raw DHT key name refers to by trying conf's resolver
+// chain in order, then looks up the tuple hosts for that key
+func (r *NameResolver) Resolve(ctx context.Context, name string) ([]*hexatype.Node, error) {
+	key, err := r.chain.Resolve(ctx, name)
+	if err != nil {
+		if err == namesys.ErrNotResolvable {
+			err = errNameNotResolved
+		}
+		return nil, err
+	}
+
+	return r.lrpc.Lookup(r.namespace, key)
+}
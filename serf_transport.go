@@ -0,0 +1,143 @@
+package kelips
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/hashicorp/serf/serf"
+	"github.com/hexablock/hexatype"
+	"github.com/hexablock/log"
+	"github.com/hexablock/vivaldi"
+)
+
+// serfMemberNode builds a hexatype.Node carrying the member's dialable
+// address/port, not just its name, so peers added via HandleJoin can
+// actually be contacted.
+func serfMemberNode(m serf.Member) *hexatype.Node {
+	return &hexatype.Node{
+		Name:    m.Name,
+		Address: m.Addr.String(),
+		Port:    uint16(m.Port),
+	}
+}
+
+const (
+	serfUserEventInsert = "kelips-insert"
+	serfUserEventDelete = "kelips-delete"
+)
+
+// serfTupleEvent is the payload carried by insert/delete user events
+type serfTupleEvent struct {
+	Namespace string
+	Key       []byte
+	Tuple     TupleHost
+}
+
+// SerfTransport is a Transport implementation backed by hashicorp/serf. It
+// replaces Kelips' bespoke heartbeat loop with serf's gossip layer for
+// failure detection and member join/leave events, and broadcasts tuple
+// mutations as serf user events so peers converge their InmemTuples without
+// a separate RPC.
+type SerfTransport struct {
+	serf     *serf.Serf
+	events   chan serf.Event
+	delegate TransportDelegate
+}
+
+// NewSerfTransport creates a SerfTransport using conf and routes observed
+// membership and tuple events to delegate
+func NewSerfTransport(conf *serf.Config, delegate TransportDelegate) (*SerfTransport, error) {
+	events := make(chan serf.Event, 256)
+	conf.EventCh = events
+
+	s, err := serf.Create(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	trans := &SerfTransport{serf: s, events: events, delegate: delegate}
+	go trans.handleEvents()
+
+	return trans, nil
+}
+
+func (t *SerfTransport) handleEvents() {
+	for e := range t.events {
+		switch ev := e.(type) {
+		case serf.MemberEvent:
+			t.handleMemberEvent(ev)
+		case serf.UserEvent:
+			t.handleUserEvent(ev)
+		}
+	}
+}
+
+func (t *SerfTransport) handleMemberEvent(ev serf.MemberEvent) {
+	switch ev.Type {
+	case serf.EventMemberJoin, serf.EventMemberUpdate:
+		for _, m := range ev.Members {
+			t.delegate.HandleJoin(serfMemberNode(m))
+		}
+	case serf.EventMemberLeave, serf.EventMemberFailed, serf.EventMemberReap:
+		for _, m := range ev.Members {
+			// Use the same identity HandleJoin stored the node under
+			// (node.Host(), built from name+address+port), not the bare
+			// serf member name, or removal routes to the wrong affinity
+			// group and the node is never evicted.
+			t.delegate.HandleLeave(serfMemberNode(m).Host())
+		}
+	}
+}
+
+func (t *SerfTransport) handleUserEvent(ev serf.UserEvent) {
+	var payload serfTupleEvent
+	if err := json.Unmarshal(ev.Payload, &payload); err != nil {
+		log.Printf("[ERROR] Failed to decode serf tuple event: %v", err)
+		return
+	}
+
+	switch ev.Name {
+	case serfUserEventInsert:
+		t.delegate.HandleInsert(payload.Namespace, payload.Key, payload.Tuple)
+	case serfUserEventDelete:
+		t.delegate.HandleDelete(payload.Namespace, payload.Key)
+	}
+}
+
+// Join contacts addrs to join the serf cluster
+func (t *SerfTransport) Join(addrs []string) error {
+	_, err := t.serf.Join(addrs, true)
+	return err
+}
+
+// Leave gracefully leaves the serf cluster
+func (t *SerfTransport) Leave() error {
+	return t.serf.Leave()
+}
+
+// Ping is a no-op for SerfTransport: failure detection is handled entirely
+// by serf's SWIM-style gossip rather than direct pings. The zero duration
+// and nil error tell callers to rely on membership events instead of
+// treating this as a failed ping.
+func (t *SerfTransport) Ping(hostname string) (*vivaldi.Coordinate, time.Duration, error) {
+	return nil, 0, nil
+}
+
+// BroadcastInsert emits a kelips-insert serf user event carrying
+// namespace/key/tuple
+func (t *SerfTransport) BroadcastInsert(namespace string, key []byte, tuple TupleHost) error {
+	b, err := json.Marshal(&serfTupleEvent{Namespace: namespace, Key: key, Tuple: tuple})
+	if err != nil {
+		return err
+	}
+	return t.serf.UserEvent(serfUserEventInsert, b, true)
+}
+
+// BroadcastDelete emits a kelips-delete serf user event for namespace/key
+func (t *SerfTransport) BroadcastDelete(namespace string, key []byte) error {
+	b, err := json.Marshal(&serfTupleEvent{Namespace: namespace, Key: key})
+	if err != nil {
+		return err
+	}
+	return t.serf.UserEvent(serfUserEventDelete, b, true)
+}
@@ -0,0 +1,280 @@
+package kelips
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/hexablock/hexatype"
+)
+
+var errTopicNotFound = errors.New("topic not found")
+
+// defaultTopicQueueSize bounds the FIFO of advertisers held per topic so a
+// handful of nodes cannot monopolize a popular topic, mirroring discv5's
+// topic table.
+const defaultTopicQueueSize = 32
+
+// topicAd is a single node's advertisement for a topic
+type topicAd struct {
+	node     *hexatype.Node
+	lastSeen int64
+	ttl      time.Duration
+}
+
+func (ad *topicAd) expired() bool {
+	return time.Now().UnixNano()-ad.lastSeen > ad.ttl.Nanoseconds()
+}
+
+// ticket grants a node the right to (re-)register for a topic once its wait
+// time has elapsed. This keeps a burst of registrations from starving the
+// advertisers already queued for a popular topic. The wait is kept shorter
+// than the advertisement's TTL so a node can refresh (keep-alive) its own
+// advertisement before it expires.
+type ticket struct {
+	issued time.Time
+	wait   time.Duration
+}
+
+func (t *ticket) ready() bool {
+	return time.Since(t.issued) >= t.wait
+}
+
+// topicQueue is a bounded FIFO of advertisers for a single topic along with
+// the wait-time tickets used to pace registrations
+type topicQueue struct {
+	mu      sync.Mutex
+	ads     []*topicAd
+	tickets map[string]*ticket
+}
+
+func newTopicQueue() *topicQueue {
+	return &topicQueue{tickets: make(map[string]*ticket)}
+}
+
+// ticketWait derives the keep-alive wait interval from a ttl: half the TTL,
+// so a node can always refresh before expiring
+func ticketWait(ttl time.Duration) time.Duration {
+	return ttl / 2
+}
+
+// register adds or refreshes node's advertisement for this queue's topic. It
+// returns an error if the node still holds an outstanding wait ticket.
+func (q *topicQueue) register(node *hexatype.Node, ttl time.Duration) error {
+	host := node.Host()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if t, ok := q.tickets[host]; ok && !t.ready() {
+		return errors.New("registration ticket not yet ready: " + host)
+	}
+
+	for _, ad := range q.ads {
+		if ad.node.Host() == host {
+			ad.node = node
+			ad.lastSeen = time.Now().UnixNano()
+			ad.ttl = ttl
+			q.tickets[host] = &ticket{issued: time.Now(), wait: ticketWait(ttl)}
+			return nil
+		}
+	}
+
+	if len(q.ads) >= defaultTopicQueueSize {
+		evicted := q.ads[0]
+		delete(q.tickets, evicted.node.Host())
+		q.ads = q.ads[1:]
+	}
+
+	q.ads = append(q.ads, &topicAd{node: node, lastSeen: time.Now().UnixNano(), ttl: ttl})
+	q.tickets[host] = &ticket{issued: time.Now(), wait: ticketWait(ttl)}
+
+	return nil
+}
+
+// expire drops advertisements whose TTL has elapsed based on LastSeen
+func (q *topicQueue) expire() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	live := q.ads[:0]
+	for _, ad := range q.ads {
+		if ad.expired() {
+			delete(q.tickets, ad.node.Host())
+			continue
+		}
+		live = append(live, ad)
+	}
+	q.ads = live
+}
+
+// nodes returns up to n advertised nodes in FIFO order
+func (q *topicQueue) nodes(n int) []*hexatype.Node {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	out := make([]*hexatype.Node, 0, n)
+	for _, ad := range q.ads {
+		if len(out) >= n {
+			break
+		}
+		out = append(out, ad.node)
+	}
+	return out
+}
+
+func (q *topicQueue) count() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.ads)
+}
+
+// topicHomeGroup hashes topic to find the affinityGroup responsible for
+// storing its advertiser queue
+func (lrpc *localGroup) topicHomeGroup(topic string) *affinityGroup {
+	h := lrpc.hashFunc()
+	h.Write([]byte(topic))
+	return lrpc.groups.get(h.Sum(nil))
+}
+
+// RegisterTopic advertises the local node under the given topic, hashing the
+// topic name to find its responsible affinityGroup and storing the
+// advertisement there so any node holding that group's state can answer
+// LookupTopic for it. Advertisements are kept in a bounded per-topic FIFO and
+// expire after ttl elapses.
+func (lrpc *localGroup) RegisterTopic(topic string, ttl time.Duration) error {
+	if lrpc.self == nil {
+		return errors.New("local node not set")
+	}
+
+	home := lrpc.topicHomeGroup(topic)
+	if err := home.topicQueue(topic).register(lrpc.self, ttl); err != nil {
+		return err
+	}
+
+	lrpc.topicMu.Lock()
+	if lrpc.selfTopics == nil {
+		lrpc.selfTopics = make(map[string]time.Duration)
+	}
+	lrpc.selfTopics[topic] = ttl
+	lrpc.topicMu.Unlock()
+
+	return nil
+}
+
+// LookupTopic returns up to n nodes advertising topic. It fans out to the
+// topic's home affinityGroup first, then widens to neighbouring groups -
+// starting from nextClosestGroup's pick - collecting each group's own
+// distinct advertisers (de-duplicated by host) until n are found or every
+// group has been visited. If no affinity group has any member nodes,
+// nextClosestGroup picks none and only the home group is searched.
+func (lrpc *localGroup) LookupTopic(topic string, n int) ([]*hexatype.Node, error) {
+	home := lrpc.topicHomeGroup(topic)
+
+	seen := make(map[string]struct{}, n)
+	nodes := make([]*hexatype.Node, 0, n)
+
+	collect := func(group *affinityGroup) {
+		tq, ok := group.peekTopicQueue(topic)
+		if !ok {
+			return
+		}
+		for _, node := range tq.nodes(defaultTopicQueueSize) {
+			if len(nodes) >= n {
+				return
+			}
+			host := node.Host()
+			if _, dup := seen[host]; dup {
+				continue
+			}
+			seen[host] = struct{}{}
+			nodes = append(nodes, node)
+		}
+	}
+
+	collect(home)
+
+	if len(nodes) < n {
+		group := lrpc.groups.nextClosestGroup(home)
+		for visited := 0; group != nil && visited < len(lrpc.groups) && len(nodes) < n; visited++ {
+			if group.index != home.index {
+				collect(group)
+			}
+
+			idx := group.index - 1
+			if idx < 0 {
+				idx = len(lrpc.groups) - 1
+			}
+			group = lrpc.groups[idx]
+		}
+	}
+
+	if len(nodes) == 0 {
+		return nil, errTopicNotFound
+	}
+
+	return nodes, nil
+}
+
+// expireTopics drops stale advertisements across every affinity group's
+// topic queues
+func (lrpc *localGroup) expireTopics() {
+	for _, group := range lrpc.groups {
+		group.expireTopics()
+	}
+}
+
+// topicAds returns the live advertisements for topic from its home group so
+// they can be gossiped out over the same heartbeat channel used for
+// pingNode.
+func (lrpc *localGroup) topicAds(topic string) []*hexatype.Node {
+	home := lrpc.topicHomeGroup(topic)
+	tq, ok := home.peekTopicQueue(topic)
+	if !ok {
+		return nil
+	}
+	return tq.nodes(defaultTopicQueueSize)
+}
+
+// selfTopicNames returns the topics this node has registered for itself
+func (lrpc *localGroup) selfTopicNames() []string {
+	lrpc.topicMu.RLock()
+	defer lrpc.topicMu.RUnlock()
+
+	names := make([]string, 0, len(lrpc.selfTopics))
+	for topic := range lrpc.selfTopics {
+		names = append(names, topic)
+	}
+	return names
+}
+
+// StartTopicGossip runs on the same goroutine cadence as the heartbeat loop
+// that drives pingNode: every tick it expires stale advertisements across
+// all groups and, if gossip is non-nil, hands it the live advertisements for
+// each topic this node has registered so they can be propagated to peers
+// (e.g. over a Transport's broadcast). It runs until stopCh is closed.
+func (lrpc *localGroup) StartTopicGossip(interval time.Duration, gossip func(topic string, ads []*hexatype.Node), stopCh <-chan struct{}) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				lrpc.expireTopics()
+				if gossip == nil {
+					continue
+				}
+				for _, topic := range lrpc.selfTopicNames() {
+					gossip(topic, lrpc.topicAds(topic))
+				}
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,86 @@
+package kelips
+
+import (
+	"time"
+
+	"github.com/hexablock/hexatype"
+	"github.com/hexablock/log"
+	"github.com/hexablock/vivaldi"
+)
+
+// Transport abstracts how Kelips discovers membership changes and
+// propagates tuple mutations to the rest of the cluster. The default is the
+// bespoke heartbeat loop driven by Config.HeartbeatInterval, which pings
+// nodes directly via affinityGroup.pingNode; a Transport lets that be
+// swapped for something like serf's gossip layer instead.
+type Transport interface {
+	// Join starts the transport and attempts to contact the given peers
+	Join(addrs []string) error
+	// Leave gracefully detaches from the cluster
+	Leave() error
+	// Ping measures the rtt and vivaldi coordinate to a peer. A zero
+	// duration indicates the transport doesn't support direct pings and
+	// relies solely on membership events for failure detection.
+	Ping(hostname string) (*vivaldi.Coordinate, time.Duration, error)
+	// BroadcastInsert propagates a tuple insertion in namespace to the rest
+	// of the cluster
+	BroadcastInsert(namespace string, key []byte, tuple TupleHost) error
+	// BroadcastDelete propagates a tuple deletion in namespace to the rest
+	// of the cluster
+	BroadcastDelete(namespace string, key []byte) error
+}
+
+// TransportDelegate receives membership and tuple events observed by a
+// Transport and applies them to the local affinity groups and tuples.
+// localGroup implements this interface.
+type TransportDelegate interface {
+	// HandleJoin is called when a peer is observed joining the cluster
+	HandleJoin(node *hexatype.Node)
+	// HandleLeave is called when a peer is observed leaving the cluster
+	HandleLeave(hostname string)
+	// HandleInsert applies a tuple insertion received from a peer
+	HandleInsert(namespace string, key []byte, tuple TupleHost)
+	// HandleDelete applies a tuple deletion received from a peer
+	HandleDelete(namespace string, key []byte)
+}
+
+// HandleJoin adds node to the affinity group responsible for its hashed
+// hostname
+func (lrpc *localGroup) HandleJoin(node *hexatype.Node) {
+	group := lrpc.hostGroup(node.Host())
+	group.addNode(node, true)
+}
+
+// HandleLeave removes the node with hostname from whichever affinity group
+// it belongs to
+func (lrpc *localGroup) HandleLeave(hostname string) {
+	group := lrpc.hostGroup(hostname)
+	group.removeNode(hostname)
+}
+
+// HandleInsert applies a tuple insertion broadcast by a remote peer so local
+// InmemTuples converge without a separate RPC round trip. It applies the
+// mutation directly rather than calling Insert, which would re-broadcast it
+// and echo it around the cluster indefinitely.
+func (lrpc *localGroup) HandleInsert(namespace string, key []byte, tuple TupleHost) {
+	if err := lrpc.applyInsert(namespace, key, tuple); err != nil {
+		log.Printf("[ERROR] Failed to apply remote tuple insert namespace=%s key=%x: %v", namespace, key, err)
+	}
+}
+
+// HandleDelete applies a tuple deletion broadcast by a remote peer. See
+// HandleInsert for why this applies the mutation directly instead of
+// calling Delete.
+func (lrpc *localGroup) HandleDelete(namespace string, key []byte) {
+	if err := lrpc.applyDelete(namespace, key); err != nil {
+		log.Printf("[ERROR] Failed to apply remote tuple delete namespace=%s key=%x: %v", namespace, key, err)
+	}
+}
+
+// hostGroup resolves the affinityGroup responsible for a hostname by hashing
+// the hostname alone, without the namespace prefix LookupGroupNodes hashes in
+func (lrpc *localGroup) hostGroup(hostname string) *affinityGroup {
+	h := lrpc.hashFunc()
+	h.Write([]byte(hostname))
+	return lrpc.groups.get(h.Sum(nil))
+}